@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// OutputLayout selects how archiveGroup lays articles out on disk.
+type OutputLayout string
+
+const (
+	LayoutSingle       OutputLayout = "single"
+	LayoutMboxPerMonth OutputLayout = "mbox-per-month"
+	LayoutMaildir      OutputLayout = "maildir"
+)
+
+// ArchiveWriter receives parsed articles in the order archiveGroup fetches
+// them and is responsible for getting them onto disk in whatever shape the
+// chosen OutputLayout calls for.
+type ArchiveWriter interface {
+	// Write persists one article. It returns the number of bytes written
+	// to the underlying mbox stream (if any), for state-file offset
+	// tracking; layouts with no single linear stream (maildir) return 0.
+	Write(article *ParsedArticle) (int64, error)
+	Close() error
+}
+
+// NewArchiveWriter builds the ArchiveWriter for the requested layout.
+// resume, when true, opens existing mbox output in append mode instead of
+// truncating it (set when an incremental --state run is resuming).
+func NewArchiveWriter(layout OutputLayout, newsgroup string, resume bool) (ArchiveWriter, error) {
+	switch layout {
+	case "", LayoutSingle:
+		return newSingleMboxWriter(newsgroup, resume)
+	case LayoutMboxPerMonth:
+		return newMonthlyMboxWriter(newsgroup)
+	case LayoutMaildir:
+		return newMaildirWriter(newsgroup)
+	default:
+		return nil, fmt.Errorf("unknown output layout %q", layout)
+	}
+}
+
+// formatMboxEntry renders an article as an mbox entry, using its parsed
+// Date when available and the current time otherwise.
+func formatMboxEntry(article *ParsedArticle) string {
+	timeStr := time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05 -0000")
+	if article.DateValid {
+		timeStr = article.Date.UTC().Format("Mon, 02 Jan 2006 15:04:05 -0000")
+	}
+	return fmt.Sprintf("From unknown %s\n%s\n\n", timeStr, article.Raw)
+}
+
+// singleMboxWriter is the original layout: every article in one
+// <group>.mbox file.
+type singleMboxWriter struct {
+	file   *os.File
+	offset int64
+}
+
+func newSingleMboxWriter(newsgroup string, resume bool) (*singleMboxWriter, error) {
+	name := strings.ReplaceAll(newsgroup, ".", "_") + ".mbox"
+	var f *os.File
+	var err error
+	var offset int64
+	if resume {
+		f, err = os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err == nil {
+			if info, statErr := f.Stat(); statErr == nil {
+				offset = info.Size()
+			}
+		}
+	} else {
+		f, err = os.Create(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &singleMboxWriter{file: f, offset: offset}, nil
+}
+
+// Write returns the mbox file's new absolute size, so callers that persist
+// a byte offset (the incremental state file) always get the true position.
+func (w *singleMboxWriter) Write(article *ParsedArticle) (int64, error) {
+	n, err := w.file.WriteString(formatMboxEntry(article))
+	if err != nil {
+		return 0, err
+	}
+	if err := w.file.Sync(); err != nil {
+		return 0, err
+	}
+	w.offset += int64(n)
+	return w.offset, nil
+}
+
+func (w *singleMboxWriter) Close() error {
+	return w.file.Close()
+}
+
+// monthlyMboxWriter writes each article into <group>/YYYY/MM.mbox, based
+// on its parsed Date (or the current time if the date couldn't be parsed),
+// keeping every month's file open for the duration of the run since
+// article dates don't arrive in strict order.
+type monthlyMboxWriter struct {
+	baseDir string
+	open    map[string]*os.File
+}
+
+func newMonthlyMboxWriter(newsgroup string) (*monthlyMboxWriter, error) {
+	baseDir := strings.ReplaceAll(newsgroup, ".", "_")
+	return &monthlyMboxWriter{baseDir: baseDir, open: make(map[string]*os.File)}, nil
+}
+
+func (w *monthlyMboxWriter) Write(article *ParsedArticle) (int64, error) {
+	date := article.Date
+	if !article.DateValid {
+		date = time.Now().UTC()
+	}
+	year := fmt.Sprintf("%04d", date.Year())
+	month := fmt.Sprintf("%02d", date.Month())
+	key := filepath.Join(year, month)
+
+	f, ok := w.open[key]
+	if !ok {
+		dir := filepath.Join(w.baseDir, year)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return 0, fmt.Errorf("creating %s: %v", dir, err)
+		}
+		path := filepath.Join(dir, month+".mbox")
+		var err error
+		f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return 0, fmt.Errorf("opening %s: %v", path, err)
+		}
+		w.open[key] = f
+	}
+
+	n, err := f.WriteString(formatMboxEntry(article))
+	if err != nil {
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	return int64(n), nil
+}
+
+func (w *monthlyMboxWriter) Close() error {
+	var firstErr error
+	for _, f := range w.open {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// maildirWriter writes each article as its own file under <group>/cur,
+// using the standard tmp/new/cur layout so the archive can be opened
+// directly by mutt, mu, or other maildir-aware tools. The filename is
+// derived from the article's Message-ID, so re-running over the same
+// articles is idempotent instead of producing duplicate files.
+type maildirWriter struct {
+	tmpDir, newDir, curDir string
+}
+
+func newMaildirWriter(newsgroup string) (*maildirWriter, error) {
+	base := strings.ReplaceAll(newsgroup, ".", "_")
+	w := &maildirWriter{
+		tmpDir: filepath.Join(base, "tmp"),
+		newDir: filepath.Join(base, "new"),
+		curDir: filepath.Join(base, "cur"),
+	}
+	for _, dir := range []string{w.tmpDir, w.newDir, w.curDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("creating %s: %v", dir, err)
+		}
+	}
+	return w, nil
+}
+
+// maildirFilename sanitizes a Message-ID into a filesystem-safe, stable
+// filename so the same article always lands on the same path.
+func maildirFilename(messageID string) string {
+	name := strings.Trim(messageID, "<>")
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	if name == "" {
+		name = "no-message-id"
+	}
+	return name + ".eml"
+}
+
+func (w *maildirWriter) Write(article *ParsedArticle) (int64, error) {
+	name := maildirFilename(article.MessageID)
+	curPath := filepath.Join(w.curDir, name)
+	if _, err := os.Stat(curPath); err == nil {
+		// Already archived in a previous run; re-runs must be idempotent.
+		return 0, nil
+	}
+
+	tmpPath := filepath.Join(w.tmpDir, name)
+	if err := os.WriteFile(tmpPath, []byte(article.Raw), 0644); err != nil {
+		return 0, fmt.Errorf("writing %s: %v", tmpPath, err)
+	}
+	if err := os.Rename(tmpPath, curPath); err != nil {
+		return 0, fmt.Errorf("renaming %s to %s: %v", tmpPath, curPath, err)
+	}
+	return 0, nil
+}
+
+func (w *maildirWriter) Close() error {
+	return nil
+}
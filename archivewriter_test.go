@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestMaildirFilename(t *testing.T) {
+	cases := []struct {
+		name      string
+		messageID string
+		want      string
+	}{
+		{
+			name:      "strips angle brackets",
+			messageID: "<abc123@news.example.com>",
+			want:      "abc123_news.example.com.eml",
+		},
+		{
+			name:      "replaces unsafe characters",
+			messageID: "<weird/id:with*chars>",
+			want:      "weird_id_with_chars.eml",
+		},
+		{
+			name:      "empty message-id falls back to a placeholder",
+			messageID: "",
+			want:      "no-message-id.eml",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := maildirFilename(c.messageID); got != c.want {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestMaildirFilenameIsDeterministic(t *testing.T) {
+	id := "<repeat-me@example.com>"
+	first := maildirFilename(id)
+	second := maildirFilename(id)
+	if first != second {
+		t.Fatalf("expected the same Message-ID to always produce the same filename, got %q and %q", first, second)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"hash/crc32"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// subjectCounterRe matches the "(n/N)" multipart counter posting agents
+// append to the subject of each part of a split binary.
+var subjectCounterRe = regexp.MustCompile(`\((\d+)/(\d+)\)`)
+
+// normalizeSubject strips a "(n/N)" counter from subject, returning the
+// stable base subject shared by every part plus the part/total it found.
+func normalizeSubject(subject string) (base string, part, total int, ok bool) {
+	m := subjectCounterRe.FindStringSubmatch(subject)
+	if m == nil {
+		return subject, 0, 0, false
+	}
+	part, _ = strconv.Atoi(m[1])
+	total, _ = strconv.Atoi(m[2])
+	base = strings.TrimSpace(subjectCounterRe.ReplaceAllString(subject, ""))
+	return base, part, total, true
+}
+
+// pendingBinary accumulates the yEnc parts posted under one multipart
+// group (same normalized subject and From) until all of them arrive.
+type pendingBinary struct {
+	total    int
+	filename string
+	parts    map[int]*yencPart
+	msgIDs   map[int]string
+}
+
+// binaryAssembler groups articles into multipart binaries by normalized
+// subject + From, decodes each part's yEnc payload, and writes the
+// reassembled file once every part of a group has been seen.
+type binaryAssembler struct {
+	dir    string
+	logger *log.Logger
+	groups map[string]*pendingBinary
+}
+
+// NewBinaryAssembler creates dir (if needed) and returns an assembler that
+// writes extracted files into it.
+func NewBinaryAssembler(dir string, logger *log.Logger) (*binaryAssembler, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating %s: %v", dir, err)
+	}
+	return &binaryAssembler{dir: dir, logger: logger, groups: make(map[string]*pendingBinary)}, nil
+}
+
+// Offer inspects article for a yEnc carrier. If it finds one, it decodes
+// it, folds it into the matching multipart group, and, once every part of
+// that group has arrived, writes the reassembled file under dir. It
+// reports isBinary so callers honoring --strip-binaries know to exclude
+// the article from the text archive even if extraction itself failed.
+func (a *binaryAssembler) Offer(article *ParsedArticle) (isBinary bool, err error) {
+	if !strings.Contains(article.Body, "=ybegin") {
+		return false, nil
+	}
+
+	part, err := parseYenc(article.Body)
+	if err != nil {
+		a.logger.Printf("yEnc: failed to decode article %s: %v", article.MessageID, err)
+		return true, nil
+	}
+
+	if part.HasPCRC32 {
+		if sum := crc32.ChecksumIEEE(part.Data); sum != part.PCRC32 {
+			a.logger.Printf("yEnc: CRC mismatch in part %d of %q (message-id %s): got %08x want %08x",
+				part.Part, part.Name, article.MessageID, sum, part.PCRC32)
+		}
+	}
+
+	total := part.Total
+	if total == 0 {
+		total = 1
+	}
+	partNum := part.Part
+	if partNum == 0 {
+		partNum = 1
+	}
+
+	base, _, _, ok := normalizeSubject(article.Subject)
+	if !ok {
+		base = article.Subject
+	}
+	key := article.From + "|" + base
+
+	group, exists := a.groups[key]
+	if !exists {
+		group = &pendingBinary{total: total, filename: part.Name, parts: make(map[int]*yencPart), msgIDs: make(map[int]string)}
+		a.groups[key] = group
+	}
+	if part.Name != "" {
+		group.filename = part.Name
+	}
+	group.parts[partNum] = part
+	group.msgIDs[partNum] = article.MessageID
+
+	if len(group.parts) < group.total {
+		return true, nil
+	}
+	delete(a.groups, key)
+	return true, a.reassemble(group)
+}
+
+// reassemble concatenates a completed group's parts in order and writes
+// the result to dir. Missing parts or an overall CRC mismatch are logged
+// with the offending message-IDs, rather than failing the whole run, so an
+// operator can refetch just those articles.
+func (a *binaryAssembler) reassemble(group *pendingBinary) error {
+	var data []byte
+	var missing []int
+	for i := 1; i <= group.total; i++ {
+		p, ok := group.parts[i]
+		if !ok {
+			missing = append(missing, i)
+			continue
+		}
+		data = append(data, p.Data...)
+	}
+	if len(missing) > 0 {
+		a.logger.Printf("yEnc: %q incomplete, missing parts %v (message-ids %v)", group.filename, missing, group.msgIDs)
+		return nil
+	}
+
+	if last, ok := group.parts[group.total]; ok && last.HasCRC32 {
+		if sum := crc32.ChecksumIEEE(data); sum != last.CRC32 {
+			a.logger.Printf("yEnc: %q overall CRC mismatch: got %08x want %08x (message-ids %v)", group.filename, sum, last.CRC32, group.msgIDs)
+		}
+	}
+
+	name := sanitizeBinaryFilename(group.filename)
+	path := filepath.Join(a.dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing extracted binary %s: %v", path, err)
+	}
+	a.logger.Printf("yEnc: extracted %q (%d bytes, %d parts) to %s", group.filename, len(data), group.total, path)
+	return nil
+}
+
+// Close flushes every group that never finished (a part expired, fell
+// outside the fetched range, or was skipped by the date filter) and logs
+// it with its missing parts and known message-IDs the same way reassemble
+// logs a mid-run gap. Without this, a group that never reaches
+// len(parts) == total simply sits in a.groups forever and Offer's own
+// missing-part detection never fires for it. Callers should call Close
+// once after the fetch loop finishes, not per-article.
+func (a *binaryAssembler) Close() {
+	for key, group := range a.groups {
+		var missing []int
+		for i := 1; i <= group.total; i++ {
+			if _, ok := group.parts[i]; !ok {
+				missing = append(missing, i)
+			}
+		}
+		a.logger.Printf("yEnc: %q never completed (%d/%d parts fetched), missing parts %v (message-ids %v)",
+			group.filename, len(group.parts), group.total, missing, group.msgIDs)
+		delete(a.groups, key)
+	}
+}
+
+// sanitizeBinaryFilename strips path separators and other characters that
+// would escape dir or confuse a filesystem, falling back to a placeholder
+// name when the subject yielded nothing usable.
+func sanitizeBinaryFilename(name string) string {
+	name = filepath.Base(strings.TrimSpace(name))
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		name = "extracted.bin"
+	}
+	return name
+}
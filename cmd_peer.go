@@ -0,0 +1,257 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// runPull implements the "pull" subcommand: fetch articles from a remote
+// peer's newsgroup into a local mbox, skipping ones already present.
+func runPull(args []string) {
+	fs := flag.NewFlagSet("pull", flag.ExitOnError)
+	server := fs.String("server", "", "NNTP server address")
+	port := fs.Int("port", 563, "NNTP server port")
+	username := fs.String("username", "", "Username for authentication")
+	password := fs.String("password", "", "Password for authentication")
+	newsgroup := fs.String("newsgroup", "", "Newsgroup to pull from")
+	useSSL := fs.Bool("ssl", true, "Use SSL connection")
+	verbose := fs.Bool("verbose", false, "Enable verbose output")
+	timeout := fs.Duration("timeout", 60*time.Second, "Timeout for operations")
+	window := fs.Int("window", defaultStreamWindow, "Maximum in-flight article fetches")
+	stateFile := fs.String("state", "", "Path to a state file shared with the archive subcommand, optional; resumes from its watermark and advances it on a clean run")
+	fs.Parse(args)
+
+	if *server == "" || *newsgroup == "" {
+		log.Fatal("pull: server and newsgroup must be specified")
+	}
+
+	logFile, err := os.Create("fetch_log.txt")
+	if err != nil {
+		log.Fatalf("pull: %v", err)
+	}
+	defer logFile.Close()
+	logger := log.New(logFile, "", log.LstdFlags)
+
+	var st *State
+	if *stateFile != "" {
+		st, err = LoadState(*stateFile)
+		if err != nil {
+			log.Fatalf("pull: loading state: %v", err)
+		}
+	}
+
+	client, err := NewNNTPClient(*server, *port, *username, *password, *useSSL, *verbose, *timeout)
+	if err != nil {
+		log.Fatalf("pull: failed to initialize client: %v", err)
+	}
+	defer client.Quit()
+
+	first, last, _, err := client.Group(*newsgroup)
+	if err != nil {
+		log.Fatalf("pull: group error: %v", err)
+	}
+
+	// low mirrors archiveGroup's incremental resume logic: if we've pulled
+	// this (server, newsgroup) before, only list overview entries above the
+	// recorded watermark instead of the whole group every time.
+	low := first
+	var gs *GroupState
+	if st != nil {
+		gs = st.Get(*server, *newsgroup)
+		if gs != nil {
+			low = gs.LastArticleNum + 1
+			if low < first {
+				logger.Printf("gap warning: watermark %d is below server's first %d (%d articles expired); resuming from first", gs.LastArticleNum, first, first-low)
+				low = first
+			}
+		}
+	}
+	if low > last {
+		logger.Printf("pull: nothing new to fetch: watermark %d already at or past last %d", low-1, last)
+		return
+	}
+
+	overviews, err := client.ListOverview(low, last)
+	if err != nil {
+		log.Fatalf("pull: failed to list overview: %v", err)
+	}
+	logger.Printf("pull: %d articles in %s %d-%d", len(overviews), *newsgroup, low, last)
+
+	mboxFileName := strings.ReplaceAll(*newsgroup, ".", "_") + ".mbox"
+	seen, err := loadSeenMessageIDs(mboxFileName)
+	if err != nil {
+		log.Fatalf("pull: failed to scan local mbox: %v", err)
+	}
+	logger.Printf("pull: %d message-ids already archived locally", len(seen))
+
+	mboxFile, err := os.OpenFile(mboxFileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatalf("pull: %v", err)
+	}
+	defer mboxFile.Close()
+
+	var mu sync.Mutex
+	var offset int64
+	store := func(ov Overview, raw string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		n, err := fmt.Fprintf(mboxFile, "From unknown %s\n%s\n\n", time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05 -0000"), raw)
+		if err != nil {
+			return err
+		}
+		if err := mboxFile.Sync(); err != nil {
+			return err
+		}
+		offset += int64(n)
+		return nil
+	}
+
+	failures, err := Pull(client, overviews, seen, *window, logger, store)
+	if err != nil {
+		log.Fatalf("pull: %v", err)
+	}
+
+	if st == nil {
+		return
+	}
+	if failures > 0 {
+		logger.Printf("pull: %d articles failed; leaving watermark at %d so a re-run can retry", failures, low-1)
+		return
+	}
+	lastMessageID := ""
+	for _, ov := range overviews {
+		if ov.Number == last {
+			lastMessageID = ov.MessageID
+			break
+		}
+	}
+	newState := &GroupState{Server: *server, Group: *newsgroup, LastArticleNum: last, LastMessageID: lastMessageID, MboxOffset: offset}
+	if gs != nil {
+		newState.MboxOffset = gs.MboxOffset + offset
+	}
+	if err := st.Set(newState); err != nil {
+		log.Fatalf("pull: saving state: %v", err)
+	}
+}
+
+// runPush implements the "push" subcommand: offer every article in a local
+// mbox to a remote peer over the streaming protocol.
+func runPush(args []string) {
+	fs := flag.NewFlagSet("push", flag.ExitOnError)
+	server := fs.String("server", "", "NNTP server address")
+	port := fs.Int("port", 563, "NNTP server port")
+	username := fs.String("username", "", "Username for authentication")
+	password := fs.String("password", "", "Password for authentication")
+	newsgroup := fs.String("newsgroup", "", "Newsgroup the local mbox corresponds to")
+	useSSL := fs.Bool("ssl", true, "Use SSL connection")
+	verbose := fs.Bool("verbose", false, "Enable verbose output")
+	timeout := fs.Duration("timeout", 60*time.Second, "Timeout for operations")
+	window := fs.Int("window", defaultStreamWindow, "Maximum in-flight CHECK/TAKETHIS exchanges")
+	fs.Parse(args)
+
+	if *server == "" || *newsgroup == "" {
+		log.Fatal("push: server and newsgroup must be specified")
+	}
+
+	logFile, err := os.Create("fetch_log.txt")
+	if err != nil {
+		log.Fatalf("push: %v", err)
+	}
+	defer logFile.Close()
+	logger := log.New(logFile, "", log.LstdFlags)
+
+	mboxFileName := strings.ReplaceAll(*newsgroup, ".", "_") + ".mbox"
+	articles, err := loadMboxArticles(mboxFileName)
+	if err != nil {
+		log.Fatalf("push: failed to read local mbox: %v", err)
+	}
+	logger.Printf("push: %d articles loaded from %s", len(articles), mboxFileName)
+
+	client, err := NewNNTPClient(*server, *port, *username, *password, *useSSL, *verbose, *timeout)
+	if err != nil {
+		log.Fatalf("push: failed to initialize client: %v", err)
+	}
+	defer client.Quit()
+
+	if err := client.StreamMode(); err != nil {
+		log.Fatalf("push: %v", err)
+	}
+
+	if err := Push(client, articles, *window, logger); err != nil {
+		log.Fatalf("push: %v", err)
+	}
+}
+
+// loadMboxArticles splits an mbox file on its "From " envelope lines and
+// parses the remainder of each message as an article.
+func loadMboxArticles(path string) ([]*ParsedArticle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var articles []*ParsedArticle
+	for _, msg := range splitMbox(string(data)) {
+		article, err := ParseArticle(msg)
+		if err != nil {
+			continue
+		}
+		articles = append(articles, article)
+	}
+	return articles, nil
+}
+
+// loadSeenMessageIDs scans an mbox file for Message-Id headers without
+// fully parsing every message, so pull can dedup cheaply.
+func loadSeenMessageIDs(path string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return seen, nil
+		}
+		return nil, err
+	}
+	for _, msg := range splitMbox(string(data)) {
+		article, err := ParseArticle(msg)
+		if err != nil || article.MessageID == "" {
+			continue
+		}
+		seen[article.MessageID] = true
+	}
+	return seen, nil
+}
+
+// splitMbox splits raw mbox content into individual messages (headers +
+// body, envelope line stripped), on lines starting with "From ".
+func splitMbox(data string) []string {
+	var messages []string
+	var cur strings.Builder
+	inMessage := false
+	lines := strings.Split(data, "\n")
+	for _, line := range lines {
+		if strings.HasPrefix(line, "From ") {
+			if inMessage {
+				messages = append(messages, strings.TrimRight(cur.String(), "\n"))
+				cur.Reset()
+			}
+			inMessage = true
+			continue
+		}
+		if inMessage {
+			cur.WriteString(line)
+			cur.WriteByte('\n')
+		}
+	}
+	if inMessage {
+		messages = append(messages, strings.TrimRight(cur.String(), "\n"))
+	}
+	return messages
+}
@@ -1,193 +1,289 @@
 package main
 
 import (
-    "bufio"
-    "crypto/tls"
-    "flag"
-    "fmt"
-    "log"
-    "net"
-    "os"
-    "strings"
-    "time"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
 )
 
-// ... (Keep existing NNTPClient struct and methods unchanged until Article) ...
-
-// New method to fetch headers using XOVER command
-func (c *NNTPClient) GetHeaders(first, last int) (map[int]bool, error) {
-    existingArticles := make(map[int]bool)
-    err := c.send(fmt.Sprintf("XOVER %d-%d", first, last))
-    if err != nil {
-        return nil, err
-    }
-    
-    resp, err := c.recv()
-    if err != nil {
-        return nil, err
-    }
-    if c.verbose {
-        fmt.Printf("XOVER response: %s\n", resp)
-    }
-    if !strings.HasPrefix(resp, "224") {
-        return nil, fmt.Errorf("XOVER failed: %s", resp)
-    }
-
-    reader := bufio.NewReader(c.conn)
-    for {
-        c.conn.SetReadDeadline(time.Now().Add(c.timeout))
-        line, err := reader.ReadString('\n')
-        if err != nil {
-            return nil, err
-        }
-        line = strings.TrimSpace(line)
-        if line == "." {
-            break
-        }
-        parts := strings.Split(line, "\t")
-        if len(parts) > 0 {
-            var articleID int
-            fmt.Sscanf(parts[0], "%d", &articleID)
-            existingArticles[articleID] = true
-        }
-    }
-    return existingArticles, nil
-}
+// archiveGroup archives a newsgroup using XOVER to find which article
+// numbers currently exist and ARTICLE to fetch each one, writing them out
+// through an ArchiveWriter for the requested layout.
+//
+// If stateFile is non-empty, the run is incremental: only articles above
+// the previously recorded watermark for (server, newsgroup) are fetched,
+// existing output is extended rather than truncated, and the watermark is
+// advanced and flushed to disk after each article so a later run can
+// resume from where this one stopped or failed.
+func archiveGroup(server string, port int, username, password, newsgroup string, useSSL, verbose bool, timeout time.Duration, startDate, endDate *time.Time, stateFile string, reset bool, layout OutputLayout, workers int, extractBinariesDir string, stripBinaries bool) error {
+	logFile, err := os.Create("fetch_log.txt")
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	logger := log.New(logFile, "", log.LstdFlags)
+	logger.Println("Starting archiveGroup")
+
+	var st *State
+	if stateFile != "" {
+		st, err = LoadState(stateFile)
+		if err != nil {
+			return fmt.Errorf("loading state: %v", err)
+		}
+		if reset {
+			if err := st.Reset(server, newsgroup); err != nil {
+				return fmt.Errorf("resetting state: %v", err)
+			}
+			logger.Printf("Reset state for %s/%s", server, newsgroup)
+		}
+	}
+
+	client, err := NewNNTPClient(server, port, username, password, useSSL, verbose, timeout)
+	if err != nil {
+		return fmt.Errorf("failed to initialize client: %v", err)
+	}
+	defer client.Quit()
+	logger.Println("NNTP client initialized")
+
+	first, last, groupResp, err := client.Group(newsgroup)
+	if err != nil {
+		return fmt.Errorf("group error: %v", err)
+	}
+	logger.Printf("GROUP response: %s", groupResp)
+	logger.Printf("First: %d, Last: %d, Range size: %d", first, last, last-first+1)
+
+	low := first
+	var gs *GroupState
+	if st != nil {
+		gs = st.Get(server, newsgroup)
+		if gs != nil {
+			low = gs.LastArticleNum + 1
+			if low < first {
+				logger.Printf("gap warning: watermark %d is below server's first %d (%d articles expired); resuming from first", gs.LastArticleNum, first, first-low)
+				low = first
+			}
+		}
+	}
+	if low > last {
+		logger.Printf("Nothing new to fetch: watermark %d already at or past last %d", low-1, last)
+		return nil
+	}
+
+	// Get existing article headers for the range we actually need.
+	existingArticles, err := client.GetHeaders(low, last)
+	if err != nil {
+		return fmt.Errorf("failed to fetch headers: %v", err)
+	}
+	logger.Printf("Found %d existing articles in %d-%d", len(existingArticles), low, last)
+
+	writer, err := NewArchiveWriter(layout, newsgroup, st != nil)
+	if err != nil {
+		return fmt.Errorf("creating archive writer: %v", err)
+	}
+	defer writer.Close()
+	var offset int64
+	logger.Printf("Opened archive writer (layout=%s)", layout)
+
+	var binaries *binaryAssembler
+	if extractBinariesDir != "" {
+		binaries, err = NewBinaryAssembler(extractBinariesDir, logger)
+		if err != nil {
+			return fmt.Errorf("setting up binary extraction: %v", err)
+		}
+	}
+
+	ids := make([]int, 0, len(existingArticles))
+	for articleID := low; articleID <= last; articleID++ {
+		if existingArticles[articleID] {
+			ids = append(ids, articleID)
+		} else {
+			logger.Printf("Article %d does not exist, skipping", articleID)
+		}
+	}
+
+	// firstFailedID is the lowest article number that could not be fetched
+	// or parsed in this run, or 0 if none yet. Articles are handed to
+	// process in ascending order by both the serial loop and
+	// NNTPPool.FetchAll, so once one is missing the watermark must stop
+	// advancing there: a later ID succeeding doesn't make the run's
+	// output contiguous again, and recording it anyway would make the
+	// next incremental run silently skip the gap forever.
+	firstFailedID := 0
+
+	// process handles one fetched article, in ascending article-number
+	// order, regardless of whether it came from the single connection
+	// below or a worker pool: parse it, apply the date-range filter, write
+	// it through the chosen layout, and advance the incremental watermark.
+	process := func(articleID int, content string, fetchErr error) error {
+		fail := func(reason string) error {
+			logger.Printf("Article %d: %s", articleID, reason)
+			if firstFailedID == 0 {
+				firstFailedID = articleID
+				logger.Printf("gap warning: watermark capped at %d; article %d and any after it that fail will need a re-run to fill in", articleID-1, articleID)
+			}
+			return nil
+		}
+		if fetchErr != nil {
+			return fail(fmt.Sprintf("error: %v", fetchErr))
+		}
+		if content == "" {
+			return fail("not fetched: empty response")
+		}
+
+		article, err := ParseArticle(content)
+		if err != nil {
+			return fail(fmt.Sprintf("failed to parse headers: %v", err))
+		}
+		if !article.DateValid {
+			logger.Printf("Article %d: could not parse Date header %q, skipping date-range filter", articleID, article.Header.Get("Date"))
+		}
 
-// Modified saveToMbox function
-func saveToMbox(server string, port int, username, password, newsgroup string, useSSL, verbose bool, timeout time.Duration, startDate, endDate *time.Time) error {
-    logFile, err := os.Create("fetch_log.txt")
-    if err != nil {
-        return err
-    }
-    defer logFile.Close()
-    logger := log.New(logFile, "", log.LstdFlags)
-    logger.Println("Starting save_to_mbox")
-
-    client, err := NewNNTPClient(server, port, username, password, useSSL, verbose, timeout)
-    if err != nil {
-        return fmt.Errorf("failed to initialize client: %v", err)
-    }
-    defer client.Quit()
-    logger.Println("NNTP client initialized")
-
-    first, last, groupResp, err := client.Group(newsgroup)
-    if err != nil {
-        return fmt.Errorf("group error: %v", err)
-    }
-    logger.Printf("GROUP response: %s", groupResp)
-    logger.Printf("First: %d, Last: %d, Range size: %d", first, last, last-first+1)
-
-    // Get existing article headers
-    existingArticles, err := client.GetHeaders(first, last)
-    if err != nil {
-        return fmt.Errorf("failed to fetch headers: %v", err)
-    }
-    logger.Printf("Found %d existing articles", len(existingArticles))
-
-    mboxFileName := strings.ReplaceAll(newsgroup, ".", "_") + ".mbox"
-    mboxFile, err := os.Create(mboxFileName)
-    if err != nil {
-        return err
-    }
-    defer mboxFile.Close()
-    logger.Println("Opened mbox file")
-
-    for articleID := last; articleID >= first; articleID-- {
-        // Check if article exists
-        if !existingArticles[articleID] {
-            logger.Printf("Article %d does not exist, skipping", articleID)
-            continue
-        }
-
-        // Fetch article content
-        content, articleResp, err := client.Article(articleID)
-        logger.Printf("ARTICLE %d response: %s", articleID, articleResp)
-        if err != nil {
-            logger.Printf("Article %d error: %v", articleID, err)
-            fmt.Printf("Error fetching article %d: %v\n", articleID, err)
-            // Attempt to reconnect
-            logger.Println("Attempting to reconnect...")
-            client.conn.Close()
-            if err := client.connect(); err != nil {
-                logger.Printf("Reconnect failed: %v", err)
-                return fmt.Errorf("reconnect failed: %v", err)
-            }
-            logger.Println("Reconnected successfully")
-            continue
-        }
-
-        if content != "" {
-            // Parse article date from content (assuming Date: header exists)
-            articleDate := time.Now() // Default to now if no date found
-            for _, line := range strings.Split(content, "\n") {
-                if strings.HasPrefix(line, "Date:") {
-                    if parsedDate, err := time.Parse(time.RFC1123, strings.TrimPrefix(line, "Date:")); err == nil {
-                        articleDate = parsedDate
-                    }
-                    break
-                }
-            }
-
-            // Check date range if specified
-            if startDate != nil && articleDate.Before(*startDate) {
-                logger.Printf("Article %d before start date, skipping", articleID)
-                continue
-            }
-            if endDate != nil && articleDate.After(*endDate) {
-                logger.Printf("Article %d after end date, skipping", articleID)
-                continue
-            }
-
-            logger.Printf("Article %d content fetched: %s...", content[:min(100, len(content))])
-            timeStr := articleDate.UTC().Format("Mon, 02 Jan 2006 15:04:05 -0000")
-            fmt.Fprintf(mboxFile, "From unknown %s\n%s\n\n", timeStr, content)
-            mboxFile.Sync()
-            logger.Printf("Article %d saved to mbox", articleID)
-        } else {
-            logger.Printf("Article %d not fetched: %s", articleID, articleResp)
-        }
-    }
-
-    logger.Println("Finished save_to_mbox")
-    return nil
+		// Check date range if specified. An unparseable date never matches
+		// a range filter, since we can't know which side of it falls.
+		if article.DateValid {
+			if startDate != nil && article.Date.Before(*startDate) {
+				logger.Printf("Article %d before start date, skipping", articleID)
+				return nil
+			}
+			if endDate != nil && article.Date.After(*endDate) {
+				logger.Printf("Article %d after end date, skipping", articleID)
+				return nil
+			}
+		}
+
+		logger.Printf("Article %d content fetched: %s...", articleID, content[:min(100, len(content))])
+
+		isBinary := false
+		if binaries != nil {
+			isBinary, err = binaries.Offer(article)
+			if err != nil {
+				return fmt.Errorf("extracting binary from article %d: %v", articleID, err)
+			}
+		}
+
+		if stripBinaries && isBinary {
+			logger.Printf("Article %d: stripped binary carrier from archive", articleID)
+		} else {
+			newOffset, err := writer.Write(article)
+			if err != nil {
+				return fmt.Errorf("writing article %d: %v", articleID, err)
+			}
+			offset = newOffset
+			logger.Printf("Article %d archived", articleID)
+		}
+
+		if st != nil && firstFailedID == 0 {
+			gs = &GroupState{
+				Server:         server,
+				Group:          newsgroup,
+				LastArticleNum: articleID,
+				LastMessageID:  article.MessageID,
+				MboxOffset:     offset,
+			}
+			if err := st.Set(gs); err != nil {
+				return fmt.Errorf("saving state after article %d: %v", articleID, err)
+			}
+		}
+		return nil
+	}
+
+	if workers > 1 {
+		logger.Printf("Fetching %d articles with %d workers", len(ids), workers)
+		pool := NewNNTPPool(server, port, username, password, useSSL, verbose, timeout, newsgroup, workers)
+		if err := pool.FetchAll(ids, logger, process); err != nil {
+			return err
+		}
+	} else {
+		for _, articleID := range ids {
+			content, articleResp, err := client.Article(articleID)
+			logger.Printf("ARTICLE %d response: %s", articleID, articleResp)
+			if err != nil {
+				logger.Printf("Article %d error: %v, reconnecting", articleID, err)
+				client.conn.Close()
+				if rerr := client.connect(); rerr != nil {
+					return fmt.Errorf("reconnect failed: %v", rerr)
+				}
+				logger.Println("Reconnected successfully")
+				if perr := process(articleID, "", err); perr != nil {
+					return perr
+				}
+				continue
+			}
+			if err := process(articleID, content, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	if binaries != nil {
+		binaries.Close()
+	}
+
+	logger.Println("Finished archiveGroup")
+	return nil
 }
 
 func main() {
-    server := flag.String("server", "", "NNTP server address")
-    port := flag.Int("port", 563, "NNTP server port")
-    username := flag.String("username", "", "Username for authentication")
-    password := flag.String("password", "", "Password for authentication")
-    newsgroup := flag.String("newsgroup", "", "Newsgroup to fetch articles from")
-    useSSL := flag.Bool("ssl", true, "Use SSL connection")
-    verbose := flag.Bool("verbose", false, "Enable verbose output")
-    timeout := flag.Duration("timeout", 60*time.Second, "Timeout for operations")
-    startDateStr := flag.String("start-date", "", "Start date (YYYY-MM-DD), optional")
-    endDateStr := flag.String("end-date", "", "End date (YYYY-MM-DD), optional")
-    flag.Parse()
-
-    if *server == "" || *username == "" || *password == "" || *newsgroup == "" {
-        log.Fatal("Server, username, password, and newsgroup must be specified")
-    }
-
-    var startDate, endDate *time.Time
-    if *startDateStr != "" {
-        if d, err := time.Parse("2006-01-02", *startDateStr); err == nil {
-            startDate = &d
-        } else {
-            log.Fatalf("Invalid start date format: %v", err)
-        }
-    }
-    if *endDateStr != "" {
-        if d, err := time.Parse("2006-01-02", *endDateStr); err == nil {
-            endDate = &d
-        } else {
-            log.Fatalf("Invalid end date format: %v", err)
-        }
-    }
-
-    err := saveToMbox(*server, *port, *username, *password, *newsgroup, *useSSL, *verbose, *timeout, startDate, endDate)
-    if err != nil {
-        log.Printf("Top-level error: %v", err)
-    }
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "pull":
+			runPull(os.Args[2:])
+			return
+		case "push":
+			runPush(os.Args[2:])
+			return
+		}
+	}
+
+	server := flag.String("server", "", "NNTP server address")
+	port := flag.Int("port", 563, "NNTP server port")
+	username := flag.String("username", "", "Username for authentication")
+	password := flag.String("password", "", "Password for authentication")
+	newsgroup := flag.String("newsgroup", "", "Newsgroup to fetch articles from")
+	useSSL := flag.Bool("ssl", true, "Use SSL connection")
+	verbose := flag.Bool("verbose", false, "Enable verbose output")
+	timeout := flag.Duration("timeout", 60*time.Second, "Timeout for operations")
+	startDateStr := flag.String("start-date", "", "Start date (YYYY-MM-DD), optional")
+	endDateStr := flag.String("end-date", "", "End date (YYYY-MM-DD), optional")
+	stateFile := flag.String("state", "", "Path to a state file for incremental/resumable archiving, optional")
+	reset := flag.Bool("reset", false, "Discard any stored watermark for this server/newsgroup before running")
+	outputLayout := flag.String("output-layout", "single", "Output layout: single, mbox-per-month, or maildir")
+	workers := flag.Int("workers", 1, "Number of concurrent connections to fetch articles with")
+	extractBinaries := flag.String("extract-binaries", "", "Directory to decode yEnc/multipart binaries into, optional")
+	stripBinaries := flag.Bool("strip-binaries", false, "Exclude extracted binary carrier articles from the text archive")
+	flag.Parse()
+
+	if *server == "" || *username == "" || *password == "" || *newsgroup == "" {
+		log.Fatal("Server, username, password, and newsgroup must be specified")
+	}
+
+	var startDate, endDate *time.Time
+	if *startDateStr != "" {
+		if d, err := time.Parse("2006-01-02", *startDateStr); err == nil {
+			startDate = &d
+		} else {
+			log.Fatalf("Invalid start date format: %v", err)
+		}
+	}
+	if *endDateStr != "" {
+		if d, err := time.Parse("2006-01-02", *endDateStr); err == nil {
+			endDate = &d
+		} else {
+			log.Fatalf("Invalid end date format: %v", err)
+		}
+	}
+
+	layout := OutputLayout(*outputLayout)
+	switch layout {
+	case LayoutSingle, LayoutMboxPerMonth, LayoutMaildir:
+	default:
+		log.Fatalf("Invalid output layout: %s", *outputLayout)
+	}
+
+	err := archiveGroup(*server, *port, *username, *password, *newsgroup, *useSSL, *verbose, *timeout, startDate, endDate, *stateFile, *reset, layout, *workers, *extractBinaries, *stripBinaries)
+	if err != nil {
+		log.Printf("Top-level error: %v", err)
+	}
 }
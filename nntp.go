@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NNTPClient wraps a net/textproto.Conn for talking to an NNTP server,
+// using ReadCodeLine/ReadDotLines/DotReader instead of hand-rolled
+// bufio scanning so multi-line responses and byte-stuffed dot-lines are
+// handled the way the protocol actually specifies.
+type NNTPClient struct {
+	conn     net.Conn
+	tp       *textproto.Conn
+	server   string
+	port     int
+	username string
+	password string
+	useSSL   bool
+	verbose  bool
+	timeout  time.Duration
+}
+
+// NewNNTPClient connects to server:port and, if credentials are given,
+// authenticates via AUTHINFO USER/PASS.
+func NewNNTPClient(server string, port int, username, password string, useSSL, verbose bool, timeout time.Duration) (*NNTPClient, error) {
+	c := &NNTPClient{
+		server:   server,
+		port:     port,
+		username: username,
+		password: password,
+		useSSL:   useSSL,
+		verbose:  verbose,
+		timeout:  timeout,
+	}
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	if username != "" {
+		if err := c.authenticate(); err != nil {
+			c.tp.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// connect dials the server, wraps the connection in a textproto.Conn, and
+// reads the initial greeting (200 posting allowed, or 201 posting prohibited).
+func (c *NNTPClient) connect() error {
+	addr := net.JoinHostPort(c.server, strconv.Itoa(c.port))
+	dialer := &net.Dialer{Timeout: c.timeout}
+
+	var conn net.Conn
+	var err error
+	if c.useSSL {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: c.server})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("dial %s: %v", addr, err)
+	}
+
+	c.conn = conn
+	c.tp = textproto.NewConn(conn)
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	code, msg, err := c.tp.ReadCodeLine(200)
+	if err != nil {
+		// Some servers reply 201 (posting prohibited) instead of 200.
+		if code != 201 {
+			c.tp.Close()
+			return fmt.Errorf("greeting: %v", err)
+		}
+	}
+	if c.verbose {
+		fmt.Printf("Connected: %d %s\n", code, msg)
+	}
+	return nil
+}
+
+func (c *NNTPClient) authenticate() error {
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	id, err := c.tp.Cmd("AUTHINFO USER %s", c.username)
+	if err != nil {
+		return err
+	}
+	c.tp.StartResponse(id)
+	code, _, err := c.tp.ReadResponse(0)
+	c.tp.EndResponse(id)
+	if err != nil {
+		return fmt.Errorf("AUTHINFO USER: %v", err)
+	}
+	if code == 381 {
+		id, err := c.tp.Cmd("AUTHINFO PASS %s", c.password)
+		if err != nil {
+			return err
+		}
+		c.tp.StartResponse(id)
+		_, _, err = c.tp.ReadCodeLine(281)
+		c.tp.EndResponse(id)
+		if err != nil {
+			return fmt.Errorf("AUTHINFO PASS: %v", err)
+		}
+	} else if code != 281 {
+		return fmt.Errorf("AUTHINFO USER: unexpected code %d", code)
+	}
+	return nil
+}
+
+// Quit sends QUIT and closes the underlying connection.
+func (c *NNTPClient) Quit() error {
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	c.tp.Cmd("QUIT")
+	return c.tp.Close()
+}
+
+// Group selects newsgroup and returns its reported first/last article numbers.
+func (c *NNTPClient) Group(newsgroup string) (first, last int, resp string, err error) {
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	id, err := c.tp.Cmd("GROUP %s", newsgroup)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	c.tp.StartResponse(id)
+	code, msg, err := c.tp.ReadCodeLine(211)
+	c.tp.EndResponse(id)
+	if err != nil {
+		return 0, 0, msg, fmt.Errorf("GROUP %s: %v", newsgroup, err)
+	}
+	// 211 <count> <first> <last> <group>
+	fields := strings.Fields(msg)
+	if len(fields) < 3 {
+		return 0, 0, msg, fmt.Errorf("GROUP %s: malformed response %q", newsgroup, msg)
+	}
+	first, err1 := strconv.Atoi(fields[1])
+	last, err2 := strconv.Atoi(fields[2])
+	if err1 != nil || err2 != nil {
+		return 0, 0, msg, fmt.Errorf("GROUP %s: malformed counters %q", newsgroup, msg)
+	}
+	return first, last, fmt.Sprintf("%d %s", code, msg), nil
+}
+
+// Article fetches the full article (headers + body) for the given number
+// and returns its raw text along with the server's status line.
+func (c *NNTPClient) Article(articleID int) (string, string, error) {
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	id, err := c.tp.Cmd("ARTICLE %d", articleID)
+	if err != nil {
+		return "", "", err
+	}
+	c.tp.StartResponse(id)
+	code, msg, err := c.tp.ReadCodeLine(220)
+	if err != nil {
+		c.tp.EndResponse(id)
+		return "", msg, fmt.Errorf("ARTICLE %d: %v", articleID, err)
+	}
+	raw, err := c.tp.ReadDotBytes()
+	c.tp.EndResponse(id)
+	if err != nil {
+		return "", msg, fmt.Errorf("ARTICLE %d: reading body: %v", articleID, err)
+	}
+	return string(raw), fmt.Sprintf("%d %s", code, msg), nil
+}
+
+// GetHeaders runs XOVER over [first, last] and returns the set of article
+// numbers the server actually reports, so callers can skip expired or
+// cancelled articles without issuing an ARTICLE for each one.
+func (c *NNTPClient) GetHeaders(first, last int) (map[int]bool, error) {
+	existingArticles := make(map[int]bool)
+
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	id, err := c.tp.Cmd("XOVER %d-%d", first, last)
+	if err != nil {
+		return nil, err
+	}
+	c.tp.StartResponse(id)
+	_, msg, err := c.tp.ReadCodeLine(224)
+	if err != nil {
+		c.tp.EndResponse(id)
+		return nil, fmt.Errorf("XOVER %d-%d: %v", first, last, err)
+	}
+	if c.verbose {
+		fmt.Printf("XOVER response: 224 %s\n", msg)
+	}
+
+	lines, err := c.tp.ReadDotLines()
+	c.tp.EndResponse(id)
+	if err != nil {
+		return nil, fmt.Errorf("XOVER %d-%d: reading overview: %v", first, last, err)
+	}
+	for _, line := range lines {
+		parts := strings.Split(line, "\t")
+		if len(parts) == 0 {
+			continue
+		}
+		articleID, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		existingArticles[articleID] = true
+	}
+	return existingArticles, nil
+}
+
+// ParsedArticle is an article's MIME header fields plus its body, as
+// produced by ParseArticle.
+type ParsedArticle struct {
+	Header     textproto.MIMEHeader
+	MessageID  string
+	Subject    string
+	From       string
+	Newsgroups string
+	Date       time.Time
+	DateValid  bool
+	Body       string
+	Raw        string
+}
+
+// ParseArticle splits a raw ARTICLE response into MIME headers and body
+// using textproto.Reader.ReadMIMEHeader, so folded headers, comments and
+// encoded words are handled the way real Usenet posts use them.
+func ParseArticle(raw string) (*ParsedArticle, error) {
+	tp := textproto.NewReader(bufio.NewReader(strings.NewReader(raw)))
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && header == nil {
+		return nil, fmt.Errorf("parsing headers: %v", err)
+	}
+
+	var body strings.Builder
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			break
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+
+	a := &ParsedArticle{
+		Header:     header,
+		MessageID:  header.Get("Message-Id"),
+		Subject:    header.Get("Subject"),
+		From:       header.Get("From"),
+		Newsgroups: header.Get("Newsgroups"),
+		Body:       body.String(),
+		Raw:        raw,
+	}
+	if d, ok := parseArticleDate(header.Get("Date")); ok {
+		a.Date = d
+		a.DateValid = true
+	}
+	return a, nil
+}
+
+// dateFallbackLayouts are tried, in order, when mail.ParseDate rejects a
+// Date: header. mail.ParseDate already covers RFC 5322/2822 plus the
+// obsolete RFC 822 two-digit-year form, but some older news software and
+// broken posting agents emit headers that match none of those exactly.
+var dateFallbackLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 06 15:04:05 -0700",
+}
+
+// parseArticleDate parses a Date: header value, preferring mail.ParseDate
+// (which understands the RFC 822 forms common on older Usenet posts) and
+// falling back to a short list of documented layouts. It reports false
+// only when every attempt fails, so callers can distinguish "no date" from
+// "couldn't parse" rather than silently substituting time.Now().
+func parseArticleDate(raw string) (time.Time, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, false
+	}
+	if t, err := mail.ParseDate(raw); err == nil {
+		return t, true
+	}
+	for _, layout := range dateFallbackLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestParseArticleDate(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		wantOK  bool
+		wantISO string // expected date in RFC3339, UTC
+	}{
+		{
+			name:    "RFC1123Z",
+			raw:     "Mon, 02 Jan 2006 15:04:05 +0000",
+			wantOK:  true,
+			wantISO: "2006-01-02T15:04:05Z",
+		},
+		{
+			name:    "RFC1123 with named zone",
+			raw:     "Mon, 02 Jan 2006 15:04:05 UTC",
+			wantOK:  true,
+			wantISO: "2006-01-02T15:04:05Z",
+		},
+		{
+			name:    "obsolete RFC822 two-digit year",
+			raw:     "Mon, 2 Jan 06 15:04:05 -0000",
+			wantOK:  true,
+			wantISO: "2006-01-02T15:04:05Z",
+		},
+		{
+			name:    "no leading weekday, numeric zone",
+			raw:     "2 Jan 2006 15:04:05 -0700",
+			wantOK:  true,
+			wantISO: "2006-01-02T22:04:05Z",
+		},
+		{
+			name:   "empty header",
+			raw:    "",
+			wantOK: false,
+		},
+		{
+			name:   "garbage",
+			raw:    "not a date at all",
+			wantOK: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := parseArticleDate(c.raw)
+			if ok != c.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, c.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if iso := got.UTC().Format("2006-01-02T15:04:05Z"); iso != c.wantISO {
+				t.Fatalf("got %s, want %s", iso, c.wantISO)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// NNTPPool fetches articles across a fixed number of independent,
+// authenticated connections, each with GROUP already selected on the
+// target newsgroup, so a caller archiving a large group isn't bottlenecked
+// on a single connection's round-trip time.
+type NNTPPool struct {
+	server             string
+	port               int
+	username, password string
+	useSSL, verbose    bool
+	timeout            time.Duration
+	newsgroup          string
+	workers            int
+}
+
+// NewNNTPPool builds a pool of the given size; workers below 1 are treated
+// as 1. Connections are opened lazily when FetchAll runs, not here.
+func NewNNTPPool(server string, port int, username, password string, useSSL, verbose bool, timeout time.Duration, newsgroup string, workers int) *NNTPPool {
+	if workers < 1 {
+		workers = 1
+	}
+	return &NNTPPool{
+		server:    server,
+		port:      port,
+		username:  username,
+		password:  password,
+		useSSL:    useSSL,
+		verbose:   verbose,
+		timeout:   timeout,
+		newsgroup: newsgroup,
+		workers:   workers,
+	}
+}
+
+// fetchResult is one article's outcome, tagged with its number so FetchAll
+// can restore order after fetches complete out of sequence.
+type fetchResult struct {
+	id      int
+	content string
+	err     error
+}
+
+// reorderWindowFactor bounds how many article fetches may be dispatched
+// ahead of the delivery cursor, as a multiple of the pool's worker count.
+// It keeps the reorder buffer small even when one worker is stuck
+// reconnecting: the dispatcher blocks instead of letting the rest of the
+// workers race ahead through the remaining IDs.
+const reorderWindowFactor = 4
+
+// FetchAll fetches every article number in ids (which must already be in
+// ascending order) across the pool's connections, and calls handle for
+// each one in that same ascending order. Fetches themselves complete out
+// of order across workers; a small reorder buffer holds finished results
+// until the ones ahead of them in ids have been handled, so callers that
+// need ordered output (appending to an mbox, advancing a watermark) don't
+// have to reorder anything themselves. The buffer is bounded to a small
+// multiple of the worker count (reorderWindowFactor): if one worker stalls
+// (e.g. reconnecting) the dispatcher stops handing out new IDs once that
+// many results are waiting undelivered, rather than letting every other
+// worker buffer the rest of a multi-hundred-thousand-article range in
+// memory. If handle returns an error, FetchAll stops and returns it once
+// the in-flight workers have drained.
+func (p *NNTPPool) FetchAll(ids []int, logger *log.Logger, handle func(id int, content string, err error) error) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	window := p.workers * reorderWindowFactor
+	sem := make(chan struct{}, window)
+	jobs := make(chan int)
+	results := make(chan fetchResult, window)
+	var wg sync.WaitGroup
+
+	for w := 0; w < p.workers; w++ {
+		wg.Add(1)
+		go p.worker(w, jobs, results, logger, &wg)
+	}
+
+	go func() {
+		for _, id := range ids {
+			sem <- struct{}{}
+			jobs <- id
+		}
+		close(jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]fetchResult, window)
+	idx := 0
+	for r := range results {
+		pending[r.id] = r
+		for idx < len(ids) {
+			ready, ok := pending[ids[idx]]
+			if !ok {
+				break
+			}
+			delete(pending, ids[idx])
+			idx++
+			<-sem
+			if err := handle(ready.id, ready.content, ready.err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// worker owns one connection for the lifetime of the pool: it authenticates,
+// selects the newsgroup, and then fetches articles from jobs until the
+// channel closes. A failed fetch triggers a reconnect of just this
+// connection, so one dropped TLS session doesn't abort the other workers.
+func (p *NNTPPool) worker(index int, jobs <-chan int, results chan<- fetchResult, logger *log.Logger, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	client, err := NewNNTPClient(p.server, p.port, p.username, p.password, p.useSSL, p.verbose, p.timeout)
+	if err != nil {
+		logger.Printf("pool worker %d: connect failed: %v", index, err)
+		for id := range jobs {
+			results <- fetchResult{id: id, err: fmt.Errorf("worker %d unavailable: %v", index, err)}
+		}
+		return
+	}
+	defer client.Quit()
+
+	if _, _, _, err := client.Group(p.newsgroup); err != nil {
+		logger.Printf("pool worker %d: GROUP failed: %v", index, err)
+	}
+
+	for id := range jobs {
+		content, _, err := client.Article(id)
+		if err != nil {
+			logger.Printf("pool worker %d: article %d error: %v, reconnecting", index, id, err)
+			client.conn.Close()
+			if rerr := client.connect(); rerr != nil {
+				logger.Printf("pool worker %d: reconnect failed: %v", index, rerr)
+				results <- fetchResult{id: id, err: err}
+				continue
+			}
+			if _, _, _, gerr := client.Group(p.newsgroup); gerr != nil {
+				logger.Printf("pool worker %d: re-GROUP after reconnect failed: %v", index, gerr)
+			}
+			content, _, err = client.Article(id)
+		}
+		results <- fetchResult{id: id, content: content, err: err}
+	}
+}
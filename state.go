@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// GroupState is the persisted watermark for a single (server, group) pair:
+// the highest article number we've successfully archived, the highest
+// Message-ID seen (for dedup/gap diagnostics), and the mbox byte offset at
+// which writing was last safely flushed.
+type GroupState struct {
+	Server         string `json:"server"`
+	Group          string `json:"group"`
+	LastArticleNum int    `json:"last_article_num"`
+	LastMessageID  string `json:"last_message_id"`
+	MboxOffset     int64  `json:"mbox_offset"`
+}
+
+// State is the on-disk state file format: one GroupState per (server, group)
+// key, keyed as "server/group" so a single file can track several runs.
+type State struct {
+	Groups map[string]*GroupState `json:"groups"`
+
+	path string
+}
+
+func stateKey(server, group string) string {
+	return server + "/" + group
+}
+
+// LoadState reads the state file at path, returning an empty State if the
+// file does not yet exist.
+func LoadState(path string) (*State, error) {
+	s := &State{Groups: make(map[string]*GroupState), path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("reading state file %s: %v", path, err)
+	}
+	if len(data) == 0 {
+		return s, nil
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parsing state file %s: %v", path, err)
+	}
+	if s.Groups == nil {
+		s.Groups = make(map[string]*GroupState)
+	}
+	s.path = path
+	return s, nil
+}
+
+// Get returns the stored state for (server, group), or nil if there is none.
+func (s *State) Get(server, group string) *GroupState {
+	return s.Groups[stateKey(server, group)]
+}
+
+// Set records the state for (server, group) and saves it to disk.
+func (s *State) Set(gs *GroupState) error {
+	s.Groups[stateKey(gs.Server, gs.Group)] = gs
+	return s.save()
+}
+
+// Reset drops any stored state for (server, group).
+func (s *State) Reset(server, group string) error {
+	delete(s.Groups, stateKey(server, group))
+	return s.save()
+}
+
+// save writes the state file atomically: write to a temp file in the same
+// directory, then rename over the real path, so a crash mid-write can never
+// leave a truncated or corrupt state file behind.
+func (s *State) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding state: %v", err)
+	}
+	dir := filepath.Dir(s.path)
+	tmp, err := os.CreateTemp(dir, ".state-*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp state file: %v", err)
+	}
+	tmpName := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("writing temp state file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return fmt.Errorf("syncing temp state file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("closing temp state file: %v", err)
+	}
+	if err := os.Rename(tmpName, s.path); err != nil {
+		os.Remove(tmpName)
+		return fmt.Errorf("renaming temp state file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadStateMissingFile(t *testing.T) {
+	s, err := LoadState(filepath.Join(t.TempDir(), "does-not-exist.json"))
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	if len(s.Groups) != 0 {
+		t.Fatalf("expected empty state, got %d groups", len(s.Groups))
+	}
+	if s.Get("news.example.com", "alt.test") != nil {
+		t.Fatalf("expected no state for an unseen group")
+	}
+}
+
+func TestStateSetGetRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+
+	gs := &GroupState{
+		Server:         "news.example.com",
+		Group:          "alt.test",
+		LastArticleNum: 42,
+		LastMessageID:  "<abc@example.com>",
+		MboxOffset:     1024,
+	}
+	if err := s.Set(gs); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// Reload from disk to make sure save() actually persisted it, not just
+	// the in-memory map.
+	reloaded, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState after Set: %v", err)
+	}
+	got := reloaded.Get("news.example.com", "alt.test")
+	if got == nil {
+		t.Fatal("expected state for alt.test after reload")
+	}
+	if *got != *gs {
+		t.Fatalf("got %+v, want %+v", got, gs)
+	}
+}
+
+func TestStateReset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+	gs := &GroupState{Server: "news.example.com", Group: "alt.test", LastArticleNum: 7}
+	if err := s.Set(gs); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := s.Reset("news.example.com", "alt.test"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if s.Get("news.example.com", "alt.test") != nil {
+		t.Fatalf("expected state to be cleared after Reset")
+	}
+
+	reloaded, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState after Reset: %v", err)
+	}
+	if reloaded.Get("news.example.com", "alt.test") != nil {
+		t.Fatalf("expected Reset to persist to disk")
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Reply codes used by the streaming feed protocol (RFC 4644).
+const (
+	codeStreamModeOK  = 203
+	codeCheckSend     = 238 // peer wants the article
+	codeCheckTryLater = 431 // peer is busy, retry later
+	codeCheckNotWant  = 438 // peer already has it, or doesn't want it
+	codeTakeAccepted  = 239
+	codeTakeRejected  = 439
+)
+
+// defaultStreamWindow bounds how many CHECK/TAKETHIS exchanges may be
+// outstanding at once when none is given explicitly.
+const defaultStreamWindow = 20
+
+// StreamMode switches the connection into streaming mode (MODE STREAM),
+// which the rest of NNTPClient's CHECK/TAKETHIS methods require.
+func (c *NNTPClient) StreamMode() error {
+	id, err := c.tp.Cmd("MODE STREAM")
+	if err != nil {
+		return err
+	}
+	c.tp.StartResponse(id)
+	_, _, err = c.tp.ReadCodeLine(codeStreamModeOK)
+	c.tp.EndResponse(id)
+	if err != nil {
+		return fmt.Errorf("MODE STREAM: %v", err)
+	}
+	return nil
+}
+
+// Check offers a message-id to the peer and returns its reply code, one of
+// codeCheckSend, codeCheckTryLater or codeCheckNotWant. Because it acquires
+// its pipeline slot through (*textproto.Conn).Cmd/StartResponse/EndResponse,
+// it is safe to call concurrently from multiple goroutines: the underlying
+// Pipeline guarantees each response is read in the same order its request
+// was written, regardless of which goroutine issued it.
+func (c *NNTPClient) Check(msgid string) (code int, err error) {
+	id, err := c.tp.Cmd("CHECK %s", msgid)
+	if err != nil {
+		return 0, err
+	}
+	c.tp.StartResponse(id)
+	code, msg, err := c.tp.ReadCodeLine(0)
+	c.tp.EndResponse(id)
+	if err != nil {
+		return 0, fmt.Errorf("CHECK %s: %v", msgid, err)
+	}
+	switch code {
+	case codeCheckSend, codeCheckTryLater, codeCheckNotWant:
+		return code, nil
+	default:
+		return code, fmt.Errorf("CHECK %s: unexpected code %d %s", msgid, code, msg)
+	}
+}
+
+// Takethis sends a full article (as returned by Article, or read from a
+// local mbox) under the streaming protocol and returns whether the peer
+// accepted it. Like Check, it is safe to call concurrently from multiple
+// goroutines, but unlike Check it cannot use (*textproto.Conn).Cmd: Cmd
+// calls EndRequest before returning, so the dot-encoded article body
+// written afterwards would be outside the pipeline's write-ordering lock
+// and could interleave on the wire with another goroutine's request. So
+// the command line and the dot body are written under a single
+// StartRequest/EndRequest pair instead.
+func (c *NNTPClient) Takethis(msgid, article string) (accepted bool, err error) {
+	id := c.tp.Next()
+	c.tp.StartRequest(id)
+	writeErr := c.tp.PrintfLine("TAKETHIS %s", msgid)
+	if writeErr == nil {
+		dw := c.tp.DotWriter()
+		if _, werr := dw.Write([]byte(article)); werr != nil {
+			writeErr = fmt.Errorf("writing article: %v", werr)
+		}
+		if cerr := dw.Close(); writeErr == nil && cerr != nil {
+			writeErr = fmt.Errorf("closing article: %v", cerr)
+		}
+	}
+	c.tp.EndRequest(id)
+	if writeErr != nil {
+		return false, fmt.Errorf("TAKETHIS %s: %v", msgid, writeErr)
+	}
+	c.tp.StartResponse(id)
+	code, msg, err := c.tp.ReadCodeLine(0)
+	c.tp.EndResponse(id)
+	if err != nil {
+		return false, fmt.Errorf("TAKETHIS %s: %v", msgid, err)
+	}
+	switch code {
+	case codeTakeAccepted:
+		return true, nil
+	case codeTakeRejected:
+		return false, nil
+	default:
+		return false, fmt.Errorf("TAKETHIS %s: unexpected code %d %s", msgid, code, msg)
+	}
+}
+
+// Overview is a single XOVER line, parsed into its standard fields
+// (article number, subject, from, date, message-id, references, bytes,
+// lines) instead of just the existence check GetHeaders does.
+type Overview struct {
+	Number     int
+	Subject    string
+	From       string
+	Date       string
+	MessageID  string
+	References string
+	Bytes      int
+	Lines      int
+}
+
+// ListOverview runs XOVER over [first, last] and parses every field of the
+// response, which pull needs to know each remote article's Message-ID.
+func (c *NNTPClient) ListOverview(first, last int) ([]Overview, error) {
+	id, err := c.tp.Cmd("XOVER %d-%d", first, last)
+	if err != nil {
+		return nil, err
+	}
+	c.tp.StartResponse(id)
+	_, _, err = c.tp.ReadCodeLine(224)
+	if err != nil {
+		c.tp.EndResponse(id)
+		return nil, fmt.Errorf("XOVER %d-%d: %v", first, last, err)
+	}
+	lines, err := c.tp.ReadDotLines()
+	c.tp.EndResponse(id)
+	if err != nil {
+		return nil, fmt.Errorf("XOVER %d-%d: reading overview: %v", first, last, err)
+	}
+
+	overviews := make([]Overview, 0, len(lines))
+	for _, line := range lines {
+		f := strings.Split(line, "\t")
+		if len(f) < 5 {
+			continue
+		}
+		num, err := strconv.Atoi(f[0])
+		if err != nil {
+			continue
+		}
+		ov := Overview{Number: num, Subject: f[1], From: f[2], Date: f[3], MessageID: f[4]}
+		if len(f) > 5 {
+			ov.References = f[5]
+		}
+		if len(f) > 6 {
+			ov.Bytes, _ = strconv.Atoi(f[6])
+		}
+		if len(f) > 7 {
+			ov.Lines, _ = strconv.Atoi(f[7])
+		}
+		overviews = append(overviews, ov)
+	}
+	return overviews, nil
+}
+
+// streamResult is one CHECK/TAKETHIS exchange's outcome, used by both Push
+// and Pull to report per-article success/failure to the log.
+type streamResult struct {
+	msgid  string
+	status string
+	err    error
+}
+
+// Push offers each article to client via CHECK, and for every message-id
+// the peer asks for, sends it via TAKETHIS. Up to window exchanges are
+// pipelined at once: a pool of goroutines calls Check/Takethis concurrently
+// (safe per the Check/Takethis doc comments), bounded by a semaphore so a
+// slow or congested peer can't make us buffer unboundedly. Results are
+// logged per message-id so operators can diff feeds; the returned error is
+// only set for connection-level failures, not individual rejections.
+func Push(client *NNTPClient, articles []*ParsedArticle, window int, logger *log.Logger) error {
+	if window < 1 {
+		window = defaultStreamWindow
+	}
+	sem := make(chan struct{}, window)
+	results := make(chan streamResult, len(articles))
+	var wg sync.WaitGroup
+
+	for _, article := range articles {
+		if article.MessageID == "" {
+			logger.Printf("push: skipping article with no Message-ID")
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(a *ParsedArticle) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			code, err := client.Check(a.MessageID)
+			if err != nil {
+				results <- streamResult{a.MessageID, "check-error", err}
+				return
+			}
+			switch code {
+			case codeCheckTryLater:
+				results <- streamResult{a.MessageID, "deferred", nil}
+				return
+			case codeCheckNotWant:
+				results <- streamResult{a.MessageID, "not-wanted", nil}
+				return
+			}
+
+			accepted, err := client.Takethis(a.MessageID, a.Raw)
+			if err != nil {
+				results <- streamResult{a.MessageID, "takethis-error", err}
+				return
+			}
+			if accepted {
+				results <- streamResult{a.MessageID, "sent", nil}
+			} else {
+				results <- streamResult{a.MessageID, "rejected", nil}
+			}
+		}(article)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for r := range results {
+		if r.err != nil {
+			logger.Printf("push %s: %s: %v", r.msgid, r.status, r.err)
+		} else {
+			logger.Printf("push %s: %s", r.msgid, r.status)
+		}
+	}
+	return nil
+}
+
+// Pull fetches every overview entry whose Message-ID is not already known
+// locally (per seen), in a pool of up to window workers, and calls store
+// for each article it successfully retrieves. Unlike Push, a real CHECK
+// round-trip would be backwards here (we already know the remote has the
+// article, since it's in its overview) so the dedup check is purely local;
+// Check/Takethis are only meaningful from the offering side. The caller is
+// expected to derive seen's low end from the incremental state file (see
+// state.go) the same way archiveGroup does, so a resumed pull doesn't have
+// to re-list or re-scan articles it already has; Pull reports how many
+// overview entries it failed to fetch or store so the caller can decide
+// whether it's safe to advance that watermark.
+func Pull(client *NNTPClient, overviews []Overview, seen map[string]bool, window int, logger *log.Logger, store func(ov Overview, raw string) error) (failures int, err error) {
+	if window < 1 {
+		window = defaultStreamWindow
+	}
+	sem := make(chan struct{}, window)
+	results := make(chan streamResult, len(overviews))
+	var wg sync.WaitGroup
+
+	for _, ov := range overviews {
+		if ov.MessageID == "" || seen[ov.MessageID] {
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(ov Overview) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			raw, _, err := client.Article(ov.Number)
+			if err != nil {
+				results <- streamResult{ov.MessageID, "fetch-error", err}
+				return
+			}
+			if err := store(ov, raw); err != nil {
+				results <- streamResult{ov.MessageID, "store-error", err}
+				return
+			}
+			results <- streamResult{ov.MessageID, "pulled", nil}
+		}(ov)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for r := range results {
+		if r.err != nil {
+			failures++
+			logger.Printf("pull %s: %s: %v", r.msgid, r.status, r.err)
+		} else {
+			logger.Printf("pull %s: %s", r.msgid, r.status)
+		}
+	}
+	return failures, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yencPart is one decoded yEnc segment: either a whole single-part binary,
+// or one numbered part (Part/Total) of a multipart post.
+type yencPart struct {
+	Name       string
+	Part       int
+	Total      int
+	Begin, End int64
+	Size       int64
+	Data       []byte
+	PCRC32     uint32
+	HasPCRC32  bool
+	CRC32      uint32
+	HasCRC32   bool
+}
+
+// parseYenc decodes a single yEnc-encoded article body: it expects exactly
+// one =ybegin...=yend block (optionally with a =ypart line for multipart
+// posts), XOR-42-decodes the data lines in between, and verifies the
+// per-part CRC32 from the =yend trailer if present.
+func parseYenc(body string) (*yencPart, error) {
+	var part yencPart
+	var dataLines []string
+	sawBegin, sawEnd := false, false
+
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "=ybegin"):
+			attrs, name := yencAttrs(line, "=ybegin")
+			part.Name = name
+			part.Part, _ = strconv.Atoi(attrs["part"])
+			part.Total, _ = strconv.Atoi(attrs["total"])
+			if sz, err := strconv.ParseInt(attrs["size"], 10, 64); err == nil {
+				part.Size = sz
+			}
+			sawBegin = true
+		case strings.HasPrefix(line, "=ypart"):
+			attrs, _ := yencAttrs(line, "=ypart")
+			if b, err := strconv.ParseInt(attrs["begin"], 10, 64); err == nil {
+				part.Begin = b
+			}
+			if e, err := strconv.ParseInt(attrs["end"], 10, 64); err == nil {
+				part.End = e
+			}
+		case strings.HasPrefix(line, "=yend"):
+			attrs, _ := yencAttrs(line, "=yend")
+			if v, ok := attrs["pcrc32"]; ok {
+				if crc, err := strconv.ParseUint(v, 16, 32); err == nil {
+					part.PCRC32, part.HasPCRC32 = uint32(crc), true
+				}
+			}
+			if v, ok := attrs["crc32"]; ok {
+				if crc, err := strconv.ParseUint(v, 16, 32); err == nil {
+					part.CRC32, part.HasCRC32 = uint32(crc), true
+				}
+			}
+			sawEnd = true
+		case sawBegin && !sawEnd:
+			dataLines = append(dataLines, line)
+		}
+	}
+
+	if !sawBegin || !sawEnd {
+		return nil, fmt.Errorf("yEnc: missing =ybegin/=yend markers")
+	}
+	data, err := decodeYencLines(dataLines)
+	if err != nil {
+		return nil, err
+	}
+	part.Data = data
+	return &part, nil
+}
+
+// yencAttrs splits a yEnc control line (=ybegin/=ypart/=yend) into its
+// key=value attributes. name is only ever present on =ybegin, and is
+// handled specially because, unlike the other attributes, it runs to the
+// end of the line and may itself contain spaces.
+func yencAttrs(line, prefix string) (attrs map[string]string, name string) {
+	rest := strings.TrimPrefix(line, prefix)
+	if idx := strings.Index(rest, " name="); idx >= 0 {
+		name = strings.TrimSpace(rest[idx+len(" name="):])
+		rest = rest[:idx]
+	}
+	attrs = make(map[string]string)
+	for _, field := range strings.Fields(rest) {
+		if kv := strings.SplitN(field, "=", 2); len(kv) == 2 {
+			attrs[kv[0]] = kv[1]
+		}
+	}
+	return attrs, name
+}
+
+// decodeYencLines reverses yEnc's XOR-42 encoding: each byte is offset by
+// 42 (mod 256), except escaped bytes (preceded by '=') which are offset by
+// a further 64 so encoders can avoid producing NUL, LF, CR, or '='.
+func decodeYencLines(lines []string) ([]byte, error) {
+	var out []byte
+	for _, line := range lines {
+		raw := []byte(line)
+		for i := 0; i < len(raw); i++ {
+			if raw[i] == '=' {
+				i++
+				if i >= len(raw) {
+					return nil, fmt.Errorf("yEnc: dangling escape at end of line")
+				}
+				out = append(out, raw[i]-42-64)
+				continue
+			}
+			out = append(out, raw[i]-42)
+		}
+	}
+	return out, nil
+}
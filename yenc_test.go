@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"hash/crc32"
+	"testing"
+)
+
+// yencEncode is the inverse of decodeYencLines, used here to build
+// known-good fixtures rather than hand-writing encoded byte strings.
+func yencEncode(data []byte) string {
+	out := make([]byte, 0, len(data))
+	for _, b := range data {
+		v := b + 42
+		switch v {
+		case 0x00, 0x0a, 0x0d, 0x3d:
+			out = append(out, '=', v+64)
+		default:
+			out = append(out, v)
+		}
+	}
+	return string(out)
+}
+
+func TestDecodeYencLines(t *testing.T) {
+	cases := []struct {
+		name    string
+		lines   []string
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name:  "plain bytes",
+			lines: []string{yencEncode([]byte("hello"))},
+			want:  []byte("hello"),
+		},
+		{
+			name:  "escaped NUL, LF, CR and equals",
+			lines: []string{yencEncode([]byte{0x00, 0x0a, 0x0d, '=', 'x'})},
+			want:  []byte{0x00, 0x0a, 0x0d, '=', 'x'},
+		},
+		{
+			name:  "multiple lines concatenate",
+			lines: []string{yencEncode([]byte("foo")), yencEncode([]byte("bar"))},
+			want:  []byte("foobar"),
+		},
+		{
+			name:    "dangling escape at end of line",
+			lines:   []string{"="},
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := decodeYencLines(c.lines)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got none (data: %q)", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != string(c.want) {
+				t.Fatalf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseYenc(t *testing.T) {
+	data := []byte("Hello, yEnc world! This is a test payload.\n")
+	sum := crc32.ChecksumIEEE(data)
+	body := "=ybegin line=128 size=44 name=test.txt\n" +
+		yencEncode(data) + "\n" +
+		fmt.Sprintf("=yend size=44 crc32=%08x\n", sum)
+
+	part, err := parseYenc(body)
+	if err != nil {
+		t.Fatalf("parseYenc: %v", err)
+	}
+	if string(part.Data) != string(data) {
+		t.Fatalf("decoded data mismatch: got %q want %q", part.Data, data)
+	}
+	if !part.HasCRC32 {
+		t.Fatalf("expected HasCRC32 to be set")
+	}
+	if part.CRC32 != sum {
+		t.Fatalf("CRC32 mismatch: got %08x want %08x", part.CRC32, sum)
+	}
+	if part.Name != "test.txt" {
+		t.Fatalf("Name mismatch: got %q", part.Name)
+	}
+
+	if got := crc32.ChecksumIEEE(part.Data); got != sum {
+		t.Fatalf("recomputed CRC32 %08x does not match trailer %08x", got, sum)
+	}
+}
+
+func TestParseYencMissingMarkers(t *testing.T) {
+	if _, err := parseYenc("just some text, no yenc markers here\n"); err == nil {
+		t.Fatal("expected error for body with no =ybegin/=yend")
+	}
+}